@@ -0,0 +1,10 @@
+package bernard
+
+import "time"
+
+// Authenticator supplies the bearer token used to authorize requests against
+// the drive API. Implementations are responsible for refreshing the token
+// before it expires.
+type Authenticator interface {
+	AccessToken() (token string, expiry time.Time, err error)
+}
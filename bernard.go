@@ -0,0 +1,215 @@
+package bernard
+
+import (
+	"context"
+
+	ds "github.com/l3uddz/bernard/datastore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Bernard synchronises the folder/file hierarchy of a single drive between
+// a Driver and a Datastore.
+type Bernard struct {
+	driver Driver
+	store  ds.Datastore
+	logger Logger
+}
+
+// New creates a Bernard that syncs driver's content into store. driver is
+// typically a drivers/googledrive.GoogleDrive or drivers/dropbox.Dropbox.
+func New(driver Driver, store ds.Datastore, opts ...Option) *Bernard {
+	b := &Bernard{
+		driver: driver,
+		store:  store,
+		logger: NopLogger,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// FullSync fetches the entire contents of driveID and writes it to the
+// Datastore, replacing whatever was previously stored for that drive.
+//
+// Deprecated: use FullSyncContext to allow the sync to be canceled.
+func (b *Bernard) FullSync(driveID string) error {
+	return b.FullSyncContext(context.Background(), driveID)
+}
+
+// FullSyncContext is FullSync with a caller-supplied context. Canceling ctx,
+// including while a retry backoff is sleeping, aborts the sync and returns
+// ErrCanceled.
+func (b *Bernard) FullSyncContext(ctx context.Context, driveID string) (err error) {
+	ctx, span := tracer.Start(ctx, "Bernard.FullSync", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	name, err := b.driver.DriveInfo(ctx, driveID)
+	if err != nil {
+		return err
+	}
+
+	pageToken, err := b.driver.PageToken(ctx, driveID)
+	if err != nil {
+		return err
+	}
+
+	folders, files, err := b.driver.AllContent(ctx, driveID)
+	if err != nil {
+		return err
+	}
+
+	drive := ds.Drive{ID: driveID, Name: name, PageToken: pageToken}
+	if err = b.store.FullSync(drive, folders, files); err != nil {
+		return err
+	}
+
+	b.logger.Info("bernard: full sync complete", "drive_id", driveID,
+		"folders", len(folders), "files", len(files), "bytes", fileBytes(files))
+	return nil
+}
+
+// PartialSync fetches only the changes for driveID since its last known page
+// token and applies them to the Datastore.
+//
+// Deprecated: use PartialSyncContext to allow the sync to be canceled.
+func (b *Bernard) PartialSync(driveID, pageToken string) error {
+	return b.PartialSyncContext(context.Background(), driveID, pageToken)
+}
+
+// PartialSyncContext is PartialSync with a caller-supplied context. Canceling
+// ctx, including while a retry backoff is sleeping, aborts the sync and
+// returns ErrCanceled.
+func (b *Bernard) PartialSyncContext(ctx context.Context, driveID, pageToken string) (err error) {
+	ctx, span := tracer.Start(ctx, "Bernard.PartialSync", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	changes, err := b.driver.ChangedContent(ctx, driveID, pageToken)
+	if err != nil {
+		return err
+	}
+
+	if err = b.store.PartialSync(changes.Drive, changes.ChangedFolders, changes.ChangedFiles, changes.RemovedIDs); err != nil {
+		return err
+	}
+
+	b.logger.Info("bernard: partial sync complete", "drive_id", driveID,
+		"folders", len(changes.ChangedFolders), "files", len(changes.ChangedFiles),
+		"removed", len(changes.RemovedIDs), "bytes", fileBytes(changes.ChangedFiles))
+	return nil
+}
+
+// FullSyncStreamContext is FullSyncContext for very large drives: folders and
+// files are streamed from the driver and applied to the Datastore one page
+// at a time, so sync memory stays at O(pageSize) instead of O(drive). It
+// returns ErrStreamingUnsupported unless both the driver implements
+// StreamingDriver and the Datastore implements ds.StreamingDatastore.
+func (b *Bernard) FullSyncStreamContext(ctx context.Context, driveID string) (err error) {
+	ctx, span := tracer.Start(ctx, "Bernard.FullSyncStream", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	streamDriver, store, err := b.streamingDeps()
+	if err != nil {
+		return err
+	}
+
+	name, err := b.driver.DriveInfo(ctx, driveID)
+	if err != nil {
+		return err
+	}
+
+	pageToken, err := b.driver.PageToken(ctx, driveID)
+	if err != nil {
+		return err
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches, errc := streamDriver.StreamAllContent(streamCtx, driveID)
+	if err = store.StreamFullSync(ds.Drive{ID: driveID, Name: name, PageToken: pageToken}, batches); err != nil {
+		cancel()
+		<-errc // drain so the producer goroutine, unblocked by cancel, can exit
+		return err
+	}
+
+	if err = <-errc; err != nil {
+		return err
+	}
+
+	b.logger.Info("bernard: streamed full sync complete", "drive_id", driveID)
+	return nil
+}
+
+// PartialSyncStreamContext is PartialSyncContext for very large drives:
+// changes are streamed from the driver and applied to the Datastore one page
+// at a time. It returns ErrStreamingUnsupported unless both the driver
+// implements StreamingDriver and the Datastore implements
+// ds.StreamingDatastore.
+func (b *Bernard) PartialSyncStreamContext(ctx context.Context, driveID, pageToken string) (err error) {
+	ctx, span := tracer.Start(ctx, "Bernard.PartialSyncStream", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	streamDriver, store, err := b.streamingDeps()
+	if err != nil {
+		return err
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches, errc := streamDriver.StreamChangedContent(streamCtx, driveID, pageToken)
+	if err = store.StreamPartialSync(ds.Drive{ID: driveID}, batches); err != nil {
+		cancel()
+		<-errc // drain so the producer goroutine, unblocked by cancel, can exit
+		return err
+	}
+
+	if err = <-errc; err != nil {
+		return err
+	}
+
+	b.logger.Info("bernard: streamed partial sync complete", "drive_id", driveID)
+	return nil
+}
+
+// streamingDeps asserts that b's driver and store support streaming,
+// returning ErrStreamingUnsupported if either does not.
+func (b *Bernard) streamingDeps() (StreamingDriver, ds.StreamingDatastore, error) {
+	streamDriver, ok := b.driver.(StreamingDriver)
+	if !ok {
+		return nil, nil, ErrStreamingUnsupported
+	}
+
+	store, ok := b.store.(ds.StreamingDatastore)
+	if !ok {
+		return nil, nil, ErrStreamingUnsupported
+	}
+
+	return streamDriver, store, nil
+}
+
+// endSpan records err on span, if any, and ends it. Shared by every sync
+// entry point so span status reporting stays consistent.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// fileBytes sums the reported size of files, for sync summary logging.
+func fileBytes(files []ds.File) uint64 {
+	var total uint64
+	for _, f := range files {
+		total += f.Size
+	}
+
+	return total
+}
@@ -0,0 +1,82 @@
+package bernard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ds "github.com/l3uddz/bernard/datastore"
+)
+
+// blockingStreamDriver emits one batch, then blocks trying to send a second
+// one until its ctx is canceled, simulating a driver still mid-page when the
+// datastore consumer bails out early.
+type blockingStreamDriver struct{ unblocked chan struct{} }
+
+func (blockingStreamDriver) PageToken(context.Context, string) (string, error)      { return "", nil }
+func (blockingStreamDriver) DriveInfo(context.Context, string) (string, error)      { return "drive", nil }
+func (blockingStreamDriver) AllContent(context.Context, string) ([]ds.Folder, []ds.File, error) {
+	return nil, nil, nil
+}
+func (blockingStreamDriver) ChangedContent(context.Context, string, string) (*ds.ChangedContent, error) {
+	return nil, nil
+}
+
+func (d blockingStreamDriver) StreamAllContent(ctx context.Context, driveID string) (<-chan ds.Batch, <-chan error) {
+	batches := make(chan ds.Batch)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer close(d.unblocked)
+		defer func() { errc <- err; close(errc) }()
+		defer close(batches)
+
+		batches <- ds.Batch{Folders: []ds.Folder{{ID: "A"}}}
+
+		select {
+		case batches <- ds.Batch{Folders: []ds.Folder{{ID: "B"}}}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}()
+
+	return batches, errc
+}
+
+func (blockingStreamDriver) StreamChangedContent(context.Context, string, string) (<-chan ds.Batch, <-chan error) {
+	panic("not used")
+}
+
+// erroringStore fails on the first batch it consumes, the way a datastore
+// write error would, leaving the producer above still trying to send.
+type erroringStore struct{}
+
+func (erroringStore) FullSync(ds.Drive, []ds.Folder, []ds.File) error        { return nil }
+func (erroringStore) PartialSync(ds.Drive, []ds.Folder, []ds.File, []string) error { return nil }
+
+func (erroringStore) StreamFullSync(drive ds.Drive, batches <-chan ds.Batch) error {
+	<-batches
+	return errors.New("datastore write failed")
+}
+
+func (erroringStore) StreamPartialSync(ds.Drive, <-chan ds.Batch) error {
+	panic("not used")
+}
+
+func TestFullSyncStreamContextCancelsProducerOnDatastoreError(t *testing.T) {
+	driver := blockingStreamDriver{unblocked: make(chan struct{})}
+	b := New(driver, erroringStore{})
+
+	err := b.FullSyncStreamContext(context.Background(), "drive")
+	if err == nil || err.Error() != "datastore write failed" {
+		t.Fatalf("FullSyncStreamContext() error = %v, want the datastore's error", err)
+	}
+
+	select {
+	case <-driver.unblocked:
+	case <-time.After(time.Second):
+		t.Fatal("producer goroutine leaked: never unblocked after the datastore consumer errored")
+	}
+}
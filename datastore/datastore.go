@@ -0,0 +1,132 @@
+package datastore
+
+import "time"
+
+// Drive represents a single shared drive being synced, along with the page
+// token marking how far the last sync progressed.
+type Drive struct {
+	ID        string
+	Name      string
+	PageToken string
+}
+
+// Owner is a single owner of a folder or file, as reported by the driver.
+type Owner struct {
+	EmailAddress string
+	DisplayName  string
+}
+
+// Folder is a directory within a drive's hierarchy.
+type Folder struct {
+	ID      string
+	Name    string
+	Parent  string
+	Trashed bool
+
+	MimeType    string
+	ModTime     time.Time
+	CreatedTime time.Time
+	TrashedTime time.Time
+	Owners      []Owner
+
+	// ShortcutTargetID is the ID of the folder this item points to, if it is
+	// a shortcut, allowing callers to dereference it. Empty otherwise.
+	ShortcutTargetID string
+	// CanDownload reports capabilities.canDownload as seen at fetch time.
+	CanDownload bool
+}
+
+// File is a leaf item within a drive's hierarchy.
+type File struct {
+	ID      string
+	Name    string
+	Parent  string
+	MD5     string
+	Size    uint64
+	Trashed bool
+
+	MimeType    string
+	ModTime     time.Time
+	CreatedTime time.Time
+	TrashedTime time.Time
+	Owners      []Owner
+
+	// ShortcutTargetID is the ID of the file this item points to, if it is
+	// a shortcut, allowing callers to dereference it. Empty otherwise.
+	ShortcutTargetID string
+	// CanDownload reports capabilities.canDownload as seen at fetch time.
+	CanDownload bool
+}
+
+// ChangedContent is the set of folders, files and removals a driver observed
+// since the page token it was asked to resume from.
+type ChangedContent struct {
+	Drive          Drive
+	ChangedFolders []Folder
+	ChangedFiles   []File
+	RemovedIDs     []string
+}
+
+// Batch is one page of a streamed sync: the folders, files and removals a
+// driver observed on a single page of results, emitted as soon as that page
+// arrives rather than after the whole drive has been fetched. For a changed-
+// content stream, Drive carries the page token observed so far; the last
+// Batch's Drive.PageToken is the one to persist once the stream completes.
+type Batch struct {
+	Drive      Drive
+	Folders    []Folder
+	Files      []File
+	RemovedIDs []string
+}
+
+// Datastore persists the folders and files discovered for a drive and
+// reports the difference between what is stored and what was just fetched.
+type Datastore interface {
+	FullSync(drive Drive, folders []Folder, files []File) error
+	PartialSync(drive Drive, folders []Folder, files []File, removed []string) error
+}
+
+// StreamingDatastore is implemented by datastores that can apply Batches as
+// they arrive instead of requiring every folder and file in memory at once.
+// Bernard's FullSyncStreamContext and PartialSyncStreamContext use it when
+// both the driver and datastore support streaming, keeping sync memory at
+// O(pageSize) instead of O(drive).
+type StreamingDatastore interface {
+	StreamFullSync(drive Drive, batches <-chan Batch) error
+	StreamPartialSync(drive Drive, batches <-chan Batch) error
+}
+
+// OrderFoldersOnHierarchy sorts folders so that a parent always appears
+// before its children, allowing callers to insert rows without violating a
+// foreign key on the parent column.
+func OrderFoldersOnHierarchy(folders []Folder) []Folder {
+	positioned := make(map[string]bool, len(folders))
+	ordered := make([]Folder, 0, len(folders))
+	remaining := folders
+
+	for len(remaining) > 0 {
+		var next []Folder
+		progressed := false
+
+		for _, folder := range remaining {
+			if _, ok := positioned[folder.Parent]; ok || folder.Parent == "" {
+				ordered = append(ordered, folder)
+				positioned[folder.ID] = true
+				progressed = true
+				continue
+			}
+
+			next = append(next, folder)
+		}
+
+		if !progressed {
+			// Parents outside this batch (e.g. the drive root); append as-is.
+			ordered = append(ordered, next...)
+			break
+		}
+
+		remaining = next
+	}
+
+	return ordered
+}
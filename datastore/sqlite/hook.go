@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	ds "github.com/l3uddz/bernard/datastore"
+)
+
+// FullSync implements ds.Datastore for drives small enough to hold entirely
+// in memory; StreamFullSync is the streaming equivalent for larger ones.
+func (db *DB) FullSync(drive ds.Drive, folders []ds.Folder, files []ds.File) error {
+	batches := make(chan ds.Batch, 1)
+	batches <- ds.Batch{Folders: folders, Files: files}
+	close(batches)
+
+	return db.StreamFullSync(drive, batches)
+}
+
+// PartialSync implements ds.Datastore for drives small enough to hold
+// entirely in memory; StreamPartialSync is the streaming equivalent for
+// larger ones.
+func (db *DB) PartialSync(drive ds.Drive, folders []ds.Folder, files []ds.File, removed []string) error {
+	batches := make(chan ds.Batch, 1)
+	batches <- ds.Batch{Drive: drive, Folders: folders, Files: files, RemovedIDs: removed}
+	close(batches)
+
+	return db.StreamPartialSync(drive, batches)
+}
+
+// Difference describes what a hook returned by NewDifferencesHook changed
+// relative to what was previously stored, e.g. allowing callers to react to
+// mtime-only changes without re-deriving them from the raw PartialSync args.
+type Difference struct {
+	AddedFolders   []ds.Folder
+	AddedFiles     []ds.File
+	ChangedFolders []FolderDifference
+	ChangedFiles   []FileDifference
+	RemovedFolders []ds.Folder
+	RemovedFiles   []ds.File
+}
+
+// FolderDifference is a single folder's state before and after a hook call.
+type FolderDifference struct {
+	Old ds.Folder
+	New ds.Folder
+}
+
+// FileDifference is a single file's state before and after a hook call.
+type FileDifference struct {
+	Old ds.File
+	New ds.File
+}
+
+// NewDifferencesHook returns a hook that applies folders, files and removed
+// the same way PartialSync does, while also recording what actually changed
+// into the returned Difference. Successive hook calls accumulate into the
+// same Difference; callers that want a fresh one per call should call
+// NewDifferencesHook again.
+func (db *DB) NewDifferencesHook() (func(drive ds.Drive, files []ds.File, folders []ds.Folder, removed []string) error, *Difference) {
+	diff := &Difference{}
+
+	hook := func(drive ds.Drive, files []ds.File, folders []ds.Folder, removed []string) error {
+		for _, folder := range folders {
+			old, found, err := db.folderByID(drive.ID, folder.ID)
+			if err != nil {
+				return fmt.Errorf("read folder %s: %w", folder.ID, err)
+			}
+
+			switch {
+			case !found:
+				diff.AddedFolders = append(diff.AddedFolders, folder)
+			case !reflect.DeepEqual(old, folder):
+				diff.ChangedFolders = append(diff.ChangedFolders, FolderDifference{Old: old, New: folder})
+			}
+		}
+
+		for _, file := range files {
+			old, found, err := db.fileByID(drive.ID, file.ID)
+			if err != nil {
+				return fmt.Errorf("read file %s: %w", file.ID, err)
+			}
+
+			switch {
+			case !found:
+				diff.AddedFiles = append(diff.AddedFiles, file)
+			case !reflect.DeepEqual(old, file):
+				diff.ChangedFiles = append(diff.ChangedFiles, FileDifference{Old: old, New: file})
+			}
+		}
+
+		for _, id := range removed {
+			if folder, found, err := db.folderByID(drive.ID, id); err != nil {
+				return fmt.Errorf("read folder %s: %w", id, err)
+			} else if found {
+				diff.RemovedFolders = append(diff.RemovedFolders, folder)
+				continue
+			}
+
+			if file, found, err := db.fileByID(drive.ID, id); err != nil {
+				return fmt.Errorf("read file %s: %w", id, err)
+			} else if found {
+				diff.RemovedFiles = append(diff.RemovedFiles, file)
+			}
+		}
+
+		return db.PartialSync(drive, folders, files, removed)
+	}
+
+	return hook, diff
+}
+
+// folderByID returns the currently stored state of folder id within
+// driveID, or found=false if it is not stored.
+func (db *DB) folderByID(driveID, id string) (folder ds.Folder, found bool, err error) {
+	var owners string
+
+	row := db.conn.QueryRow(
+		`SELECT id, name, parent, trashed,
+			mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+		 FROM folders WHERE id = ? AND drive_id = ?`, id, driveID,
+	)
+	if err := row.Scan(
+		&folder.ID, &folder.Name, &folder.Parent, &folder.Trashed,
+		&folder.MimeType, &folder.ModTime, &folder.CreatedTime, &folder.TrashedTime, &owners, &folder.ShortcutTargetID, &folder.CanDownload,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return ds.Folder{}, false, nil
+		}
+		return ds.Folder{}, false, err
+	}
+
+	if err := json.Unmarshal([]byte(owners), &folder.Owners); err != nil {
+		return ds.Folder{}, false, fmt.Errorf("unmarshal owners for folder %s: %w", id, err)
+	}
+
+	return folder, true, nil
+}
+
+// fileByID returns the currently stored state of file id within driveID, or
+// found=false if it is not stored.
+func (db *DB) fileByID(driveID, id string) (file ds.File, found bool, err error) {
+	var owners string
+
+	row := db.conn.QueryRow(
+		`SELECT id, name, parent, md5, size, trashed,
+			mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+		 FROM files WHERE id = ? AND drive_id = ?`, id, driveID,
+	)
+	if err := row.Scan(
+		&file.ID, &file.Name, &file.Parent, &file.MD5, &file.Size, &file.Trashed,
+		&file.MimeType, &file.ModTime, &file.CreatedTime, &file.TrashedTime, &owners, &file.ShortcutTargetID, &file.CanDownload,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return ds.File{}, false, nil
+		}
+		return ds.File{}, false, err
+	}
+
+	if err := json.Unmarshal([]byte(owners), &file.Owners); err != nil {
+		return ds.File{}, false, fmt.Errorf("unmarshal owners for file %s: %w", id, err)
+	}
+
+	return file, true, nil
+}
@@ -0,0 +1,139 @@
+package sqlite
+
+import "database/sql"
+
+// migrations are SQL statements applied in order to bring a database up to
+// the current schema. Each must be safe to run exactly once; Migrate tracks
+// how many have already been applied in schema_version.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS drives (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		page_token TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS folders (
+		id TEXT PRIMARY KEY,
+		drive_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		parent TEXT NOT NULL,
+		trashed BOOLEAN NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS files (
+		id TEXT PRIMARY KEY,
+		drive_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		parent TEXT NOT NULL,
+		md5 TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		trashed BOOLEAN NOT NULL
+	)`,
+	// Per-item metadata: modified/created/trashed times, owners, mimeType,
+	// shortcut target and download capability.
+	`ALTER TABLE folders ADD COLUMN mime_type TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE folders ADD COLUMN modified_time TIMESTAMP`,
+	`ALTER TABLE folders ADD COLUMN created_time TIMESTAMP`,
+	`ALTER TABLE folders ADD COLUMN trashed_time TIMESTAMP`,
+	`ALTER TABLE folders ADD COLUMN owners_json TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE folders ADD COLUMN shortcut_target_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE folders ADD COLUMN can_download BOOLEAN NOT NULL DEFAULT 1`,
+	`ALTER TABLE files ADD COLUMN mime_type TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE files ADD COLUMN modified_time TIMESTAMP`,
+	`ALTER TABLE files ADD COLUMN created_time TIMESTAMP`,
+	`ALTER TABLE files ADD COLUMN trashed_time TIMESTAMP`,
+	`ALTER TABLE files ADD COLUMN owners_json TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE files ADD COLUMN shortcut_target_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE files ADD COLUMN can_download BOOLEAN NOT NULL DEFAULT 1`,
+	// Re-key folders/files on (drive_id, id) instead of id alone: a driver
+	// whose IDs aren't globally unique (e.g. Dropbox, keyed by lowercased
+	// path) can otherwise report the same id for two different drives,
+	// and a single global PRIMARY KEY would let one drive's sync overwrite
+	// or block another's. SQLite can't alter a PRIMARY KEY in place, so
+	// each table is rebuilt.
+	`CREATE TABLE folders_new (
+		id TEXT NOT NULL,
+		drive_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		parent TEXT NOT NULL,
+		trashed BOOLEAN NOT NULL,
+		mime_type TEXT NOT NULL DEFAULT '',
+		modified_time TIMESTAMP,
+		created_time TIMESTAMP,
+		trashed_time TIMESTAMP,
+		owners_json TEXT NOT NULL DEFAULT '[]',
+		shortcut_target_id TEXT NOT NULL DEFAULT '',
+		can_download BOOLEAN NOT NULL DEFAULT 1,
+		PRIMARY KEY (drive_id, id)
+	)`,
+	`INSERT INTO folders_new (
+		id, drive_id, name, parent, trashed,
+		mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+	) SELECT
+		id, drive_id, name, parent, trashed,
+		mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+	FROM folders`,
+	`DROP TABLE folders`,
+	`ALTER TABLE folders_new RENAME TO folders`,
+	`CREATE TABLE files_new (
+		id TEXT NOT NULL,
+		drive_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		parent TEXT NOT NULL,
+		md5 TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		trashed BOOLEAN NOT NULL,
+		mime_type TEXT NOT NULL DEFAULT '',
+		modified_time TIMESTAMP,
+		created_time TIMESTAMP,
+		trashed_time TIMESTAMP,
+		owners_json TEXT NOT NULL DEFAULT '[]',
+		shortcut_target_id TEXT NOT NULL DEFAULT '',
+		can_download BOOLEAN NOT NULL DEFAULT 1,
+		PRIMARY KEY (drive_id, id)
+	)`,
+	`INSERT INTO files_new (
+		id, drive_id, name, parent, md5, size, trashed,
+		mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+	) SELECT
+		id, drive_id, name, parent, md5, size, trashed,
+		mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+	FROM files`,
+	`DROP TABLE files`,
+	`ALTER TABLE files_new RENAME TO files`,
+}
+
+// Migrate brings conn's schema up to date, creating the drives/folders/files
+// tables if they don't exist yet and applying any migrations not already
+// recorded in schema_version. It is safe to call on every startup.
+func Migrate(conn *sql.DB) error {
+	if _, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+
+	var rows int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&rows); err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := conn.Exec(`INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return err
+		}
+	}
+
+	var version int
+	if err := conn.QueryRow(`SELECT version FROM schema_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for _, stmt := range migrations[version:] {
+		if _, err := conn.Exec(stmt); err != nil {
+			return err
+		}
+
+		version++
+		if _, err := conn.Exec(`UPDATE schema_version SET version = ?`, version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+// Package sqlite is a bernard.Datastore backed by a SQL database.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	ds "github.com/l3uddz/bernard/datastore"
+)
+
+// streamBatchSize bounds how many folder/file rows are committed per
+// transaction while streaming a sync, so memory stays at O(pageSize)
+// instead of O(drive).
+const streamBatchSize = 5000
+
+// DB is a bernard.datastore.StreamingDatastore backed by database/sql,
+// for drives too large to sync through the batch-oriented FullSync and
+// PartialSync, which require every folder and file in memory at once.
+type DB struct {
+	conn *sql.DB
+}
+
+// NewDB wraps an already-open database connection, e.g. one returned by
+// sql.Open("sqlite3", path), migrating it to the current schema.
+func NewDB(conn *sql.DB) (*DB, error) {
+	if err := Migrate(conn); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &DB{conn: conn}, nil
+}
+
+// streamTx accumulates rows across a streamed sync, committing every
+// streamBatchSize rows and resolving folder hierarchy incrementally: a
+// folder arriving before its parent is queued in pending until that parent
+// is inserted, rather than requiring the whole drive's folders up front.
+type streamTx struct {
+	conn *sql.DB
+	tx   *sql.Tx
+	rows int
+
+	inserted map[string]bool
+	pending  map[string][]ds.Folder
+}
+
+func newStreamTx(conn *sql.DB) (*streamTx, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamTx{
+		conn:     conn,
+		tx:       tx,
+		inserted: map[string]bool{"": true},
+		pending:  map[string][]ds.Folder{},
+	}, nil
+}
+
+func (s *streamTx) exec(query string, args ...interface{}) error {
+	if _, err := s.tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows >= streamBatchSize {
+		if err := s.tx.Commit(); err != nil {
+			return err
+		}
+
+		tx, err := s.conn.Begin()
+		if err != nil {
+			return err
+		}
+
+		s.tx = tx
+		s.rows = 0
+	}
+
+	return nil
+}
+
+func (s *streamTx) insertFolder(driveID string, folder ds.Folder) error {
+	owners, err := json.Marshal(folder.Owners)
+	if err != nil {
+		return fmt.Errorf("marshal owners for folder %s: %w", folder.ID, err)
+	}
+
+	if err := s.exec(
+		`INSERT INTO folders (
+			id, drive_id, name, parent, trashed,
+			mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (drive_id, id) DO UPDATE SET
+			name = excluded.name, parent = excluded.parent, trashed = excluded.trashed,
+			mime_type = excluded.mime_type, modified_time = excluded.modified_time, created_time = excluded.created_time,
+			trashed_time = excluded.trashed_time, owners_json = excluded.owners_json,
+			shortcut_target_id = excluded.shortcut_target_id, can_download = excluded.can_download`,
+		folder.ID, driveID, folder.Name, folder.Parent, folder.Trashed,
+		folder.MimeType, folder.ModTime, folder.CreatedTime, folder.TrashedTime, string(owners), folder.ShortcutTargetID, folder.CanDownload,
+	); err != nil {
+		return fmt.Errorf("insert folder %s: %w", folder.ID, err)
+	}
+
+	s.inserted[folder.ID] = true
+
+	ready := s.pending[folder.ID]
+	delete(s.pending, folder.ID)
+
+	for _, child := range ready {
+		if err := s.insertFolder(driveID, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *streamTx) addFolders(driveID string, folders []ds.Folder) error {
+	for _, folder := range folders {
+		if s.inserted[folder.Parent] {
+			if err := s.insertFolder(driveID, folder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s.pending[folder.Parent] = append(s.pending[folder.Parent], folder)
+	}
+
+	return nil
+}
+
+func (s *streamTx) addFiles(driveID string, files []ds.File) error {
+	for _, file := range files {
+		owners, err := json.Marshal(file.Owners)
+		if err != nil {
+			return fmt.Errorf("marshal owners for file %s: %w", file.ID, err)
+		}
+
+		if err := s.exec(
+			`INSERT INTO files (
+				id, drive_id, name, parent, md5, size, trashed,
+				mime_type, modified_time, created_time, trashed_time, owners_json, shortcut_target_id, can_download
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (drive_id, id) DO UPDATE SET
+				name = excluded.name, parent = excluded.parent, md5 = excluded.md5,
+				size = excluded.size, trashed = excluded.trashed, mime_type = excluded.mime_type,
+				modified_time = excluded.modified_time, created_time = excluded.created_time, trashed_time = excluded.trashed_time,
+				owners_json = excluded.owners_json, shortcut_target_id = excluded.shortcut_target_id, can_download = excluded.can_download`,
+			file.ID, driveID, file.Name, file.Parent, file.MD5, file.Size, file.Trashed,
+			file.MimeType, file.ModTime, file.CreatedTime, file.TrashedTime, string(owners), file.ShortcutTargetID, file.CanDownload,
+		); err != nil {
+			return fmt.Errorf("insert file %s: %w", file.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *streamTx) removeIDs(driveID string, ids []string) error {
+	for _, id := range ids {
+		if err := s.exec(`DELETE FROM folders WHERE id = ? AND drive_id = ?`, id, driveID); err != nil {
+			return err
+		}
+		if err := s.exec(`DELETE FROM files WHERE id = ? AND drive_id = ?`, id, driveID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finish flushes any remaining folders still waiting on a parent that never
+// arrived (e.g. the drive root) and commits the final transaction.
+func (s *streamTx) finish(driveID string) error {
+	for _, folders := range s.pending {
+		for _, folder := range folders {
+			if err := s.insertFolder(driveID, folder); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.tx.Commit()
+}
+
+func (s *streamTx) rollback() {
+	s.tx.Rollback()
+}
+
+// StreamFullSync implements ds.StreamingDatastore. It replaces driveID's
+// stored folders and files with batches as they arrive.
+func (db *DB) StreamFullSync(drive ds.Drive, batches <-chan ds.Batch) error {
+	s, err := newStreamTx(db.conn)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.tx.Exec(`DELETE FROM folders WHERE drive_id = ?`, drive.ID); err != nil {
+		s.rollback()
+		return err
+	}
+	if _, err := s.tx.Exec(`DELETE FROM files WHERE drive_id = ?`, drive.ID); err != nil {
+		s.rollback()
+		return err
+	}
+
+	if err := s.consume(drive, batches); err != nil {
+		return err
+	}
+
+	if err := upsertDrive(s.tx, drive); err != nil {
+		s.rollback()
+		return err
+	}
+
+	return s.finish(drive.ID)
+}
+
+// StreamPartialSync implements ds.StreamingDatastore. It applies batches as
+// a set of incremental changes to driveID's existing folders and files.
+func (db *DB) StreamPartialSync(drive ds.Drive, batches <-chan ds.Batch) error {
+	s, err := newStreamTx(db.conn)
+	if err != nil {
+		return err
+	}
+
+	var lastDrive = drive
+
+	for batch := range batches {
+		if batch.Drive.PageToken != "" {
+			lastDrive = batch.Drive
+		}
+
+		if err := s.removeIDs(lastDrive.ID, batch.RemovedIDs); err != nil {
+			s.rollback()
+			return err
+		}
+		if err := s.addFolders(lastDrive.ID, batch.Folders); err != nil {
+			s.rollback()
+			return err
+		}
+		if err := s.addFiles(lastDrive.ID, batch.Files); err != nil {
+			s.rollback()
+			return err
+		}
+	}
+
+	if err := upsertDrive(s.tx, lastDrive); err != nil {
+		s.rollback()
+		return err
+	}
+
+	return s.finish(lastDrive.ID)
+}
+
+func (s *streamTx) consume(drive ds.Drive, batches <-chan ds.Batch) error {
+	for batch := range batches {
+		if err := s.removeIDs(drive.ID, batch.RemovedIDs); err != nil {
+			s.rollback()
+			return err
+		}
+		if err := s.addFolders(drive.ID, batch.Folders); err != nil {
+			s.rollback()
+			return err
+		}
+		if err := s.addFiles(drive.ID, batch.Files); err != nil {
+			s.rollback()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upsertDrive stores drive, preserving the previously-stored name when
+// drive.Name is empty: a partial sync's lastDrive is only populated with a
+// real name when a change page happens to report a drive rename, so
+// treating an empty name as "no rename to apply" avoids blanking it on
+// every other partial sync.
+func upsertDrive(tx *sql.Tx, drive ds.Drive) error {
+	_, err := tx.Exec(
+		`INSERT INTO drives (id, name, page_token) VALUES (?, ?, ?)
+		 ON CONFLICT (id) DO UPDATE SET
+			name = CASE WHEN excluded.name = '' THEN drives.name ELSE excluded.name END,
+			page_token = excluded.page_token`,
+		drive.ID, drive.Name, drive.PageToken,
+	)
+	return err
+}
@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	ds "github.com/l3uddz/bernard/datastore"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := NewDB(conn)
+	if err != nil {
+		t.Fatalf("NewDB: %s", err)
+	}
+
+	return db
+}
+
+func streamBatch(t *testing.T, fn func(batches <-chan ds.Batch) error, batches ...ds.Batch) error {
+	t.Helper()
+
+	ch := make(chan ds.Batch)
+	done := make(chan error, 1)
+	go func() { done <- fn(ch) }()
+
+	for _, b := range batches {
+		ch <- b
+	}
+	close(ch)
+
+	return <-done
+}
+
+func TestStreamPartialSyncUpsertsExistingRows(t *testing.T) {
+	db := openTestDB(t)
+	drive := ds.Drive{ID: "drive", Name: "Drive", PageToken: "1"}
+
+	err := streamBatch(t, func(batches <-chan ds.Batch) error {
+		return db.StreamFullSync(drive, batches)
+	}, ds.Batch{
+		Folders: []ds.Folder{{ID: "A", Parent: "", Name: "Folder A"}},
+		Files:   []ds.File{{ID: "Z", Parent: "A", Name: "File Z", MD5: "old"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamFullSync: %s", err)
+	}
+
+	// Re-streaming the same IDs with changed fields must update the existing
+	// rows rather than failing on the folders/files PRIMARY KEY.
+	err = streamBatch(t, func(batches <-chan ds.Batch) error {
+		return db.StreamPartialSync(drive, batches)
+	}, ds.Batch{
+		Drive:   ds.Drive{ID: "drive", Name: "Drive", PageToken: "2"},
+		Folders: []ds.Folder{{ID: "A", Parent: "", Name: "Folder A renamed"}},
+		Files:   []ds.File{{ID: "Z", Parent: "A", Name: "File Z", MD5: "new"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamPartialSync: %s", err)
+	}
+
+	var folderName, fileMD5 string
+	if err := db.conn.QueryRow(`SELECT name FROM folders WHERE id = ?`, "A").Scan(&folderName); err != nil {
+		t.Fatalf("query folder: %s", err)
+	}
+	if folderName != "Folder A renamed" {
+		t.Errorf("folder name = %q, want %q", folderName, "Folder A renamed")
+	}
+
+	if err := db.conn.QueryRow(`SELECT md5 FROM files WHERE id = ?`, "Z").Scan(&fileMD5); err != nil {
+		t.Fatalf("query file: %s", err)
+	}
+	if fileMD5 != "new" {
+		t.Errorf("file md5 = %q, want %q", fileMD5, "new")
+	}
+}
+
+func TestStreamPartialSyncPreservesDriveNameWhenNotReported(t *testing.T) {
+	db := openTestDB(t)
+
+	err := streamBatch(t, func(batches <-chan ds.Batch) error {
+		return db.StreamFullSync(ds.Drive{ID: "drive", Name: "My Drive", PageToken: "1"}, batches)
+	}, ds.Batch{Folders: []ds.Folder{{ID: "A"}}})
+	if err != nil {
+		t.Fatalf("StreamFullSync: %s", err)
+	}
+
+	// An ordinary partial sync (no drive-metadata-changed event in the page)
+	// reports no drive name at all; it must not blank out the one already
+	// stored.
+	err = db.PartialSync(ds.Drive{ID: "drive", Name: "", PageToken: "2"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("PartialSync: %s", err)
+	}
+
+	var name string
+	if err := db.conn.QueryRow(`SELECT name FROM drives WHERE id = ?`, "drive").Scan(&name); err != nil {
+		t.Fatalf("query drive: %s", err)
+	}
+	if name != "My Drive" {
+		t.Errorf("drive name = %q, want %q", name, "My Drive")
+	}
+}
+
+func TestStreamFullSyncKeepsSameIDFoldersFromDifferentDrivesSeparate(t *testing.T) {
+	db := openTestDB(t)
+
+	// Two drives reusing the same folder ID (e.g. two Dropbox accounts
+	// sharing an absolute path) must both be stored, each under its own
+	// drive, rather than one clobbering or blocking the other.
+	driveA := ds.Drive{ID: "driveA", Name: "Drive A", PageToken: "1"}
+	err := streamBatch(t, func(batches <-chan ds.Batch) error {
+		return db.StreamFullSync(driveA, batches)
+	}, ds.Batch{
+		Folders: []ds.Folder{{ID: "shared-path", Parent: "", Name: "Drive A's folder"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamFullSync(driveA): %s", err)
+	}
+
+	driveB := ds.Drive{ID: "driveB", Name: "Drive B", PageToken: "1"}
+	err = streamBatch(t, func(batches <-chan ds.Batch) error {
+		return db.StreamFullSync(driveB, batches)
+	}, ds.Batch{
+		Folders: []ds.Folder{{ID: "shared-path", Parent: "", Name: "Drive B's folder"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamFullSync(driveB): %s", err)
+	}
+
+	for driveID, wantName := range map[string]string{"driveA": "Drive A's folder", "driveB": "Drive B's folder"} {
+		var name string
+		if err := db.conn.QueryRow(`SELECT name FROM folders WHERE id = ? AND drive_id = ?`, "shared-path", driveID).Scan(&name); err != nil {
+			t.Fatalf("query folder for %s: %s", driveID, err)
+		}
+		if name != wantName {
+			t.Errorf("%s's folder name = %q, want %q", driveID, name, wantName)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package sqlite
+
+import "testing"
+
+// setupTest returns a DB backed by a fresh in-memory sqlite connection, for
+// tests that exercise the non-streaming ds.Datastore methods.
+func setupTest(t *testing.T) *DB {
+	return openTestDB(t)
+}
@@ -0,0 +1,44 @@
+package bernard
+
+import (
+	"context"
+
+	ds "github.com/l3uddz/bernard/datastore"
+)
+
+// Driver is the backend-specific half of a sync: it knows how to talk to one
+// remote API and translate its responses into Bernard's folder/file model.
+// The Google Drive v3 implementation lives in drivers/googledrive; other
+// backends (e.g. drivers/dropbox) implement the same interface so Bernard can
+// sync them into the same Datastore.
+type Driver interface {
+	// PageToken returns a token marking the current head of driveID's change
+	// stream, for use as the starting point of a later ChangedContent call.
+	PageToken(ctx context.Context, driveID string) (string, error)
+
+	// DriveInfo returns the display name of driveID.
+	DriveInfo(ctx context.Context, driveID string) (name string, err error)
+
+	// AllContent returns every folder and file currently in driveID.
+	AllContent(ctx context.Context, driveID string) ([]ds.Folder, []ds.File, error)
+
+	// ChangedContent returns everything that changed in driveID since
+	// pageToken was issued.
+	ChangedContent(ctx context.Context, driveID string, pageToken string) (*ds.ChangedContent, error)
+}
+
+// StreamingDriver is implemented by drivers that can emit AllContent and
+// ChangedContent one page at a time instead of buffering the entire result
+// in memory. Bernard's FullSyncStreamContext and PartialSyncStreamContext
+// use it when available, keeping sync memory at O(pageSize) instead of
+// O(drive). Both channels are closed once the stream ends; a send on the
+// error channel is the final value the caller will receive.
+type StreamingDriver interface {
+	// StreamAllContent is AllContent, emitting one ds.Batch per page fetched.
+	StreamAllContent(ctx context.Context, driveID string) (<-chan ds.Batch, <-chan error)
+
+	// StreamChangedContent is ChangedContent, emitting one ds.Batch per page
+	// fetched. Each batch's Drive.PageToken reflects progress so far; the
+	// last batch sent before the channel closes carries the token to persist.
+	StreamChangedContent(ctx context.Context, driveID string, pageToken string) (<-chan ds.Batch, <-chan error)
+}
@@ -0,0 +1,490 @@
+// Package dropbox implements bernard.Driver against the Dropbox v2 API.
+//
+// Dropbox has no notion of a shared-drive ID; driveID is instead the path of
+// the folder to sync (use "" for the whole Dropbox). Its list_folder cursor
+// fills the same role as a Drive changes pageToken.
+package dropbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/l3uddz/bernard"
+	ds "github.com/l3uddz/bernard/datastore"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const baseURL = "https://api.dropboxapi.com/2"
+
+// tracer emits a span per withAuth call and per bernard.Driver method, so a
+// full-sync or partial-sync can be traced end-to-end alongside Bernard's own
+// spans in a larger service.
+var tracer = otel.Tracer("github.com/l3uddz/bernard/drivers/dropbox")
+
+// Dropbox is a bernard.Driver backed by the Dropbox v2 API.
+type Dropbox struct {
+	auth    bernard.Authenticator
+	baseURL string
+	client  *http.Client
+	logger  bernard.Logger
+
+	pacer bernard.PacerHolder
+
+	shouldRetry ShouldRetry
+	decodeJSON  jsonDecoder
+}
+
+// New creates a Dropbox driver that authorizes requests using auth. Its
+// Pacer is created lazily on first use (see bernard.PacerHolder), so
+// supplying one via WithPacer never leaves a default one running unused.
+func New(auth bernard.Authenticator, opts ...Option) *Dropbox {
+	db := &Dropbox{
+		auth:        auth,
+		baseURL:     baseURL,
+		client:      http.DefaultClient,
+		logger:      bernard.NopLogger,
+		shouldRetry: defaultShouldRetry,
+		decodeJSON:  decodeJSON,
+	}
+
+	for _, opt := range opts {
+		opt(db)
+	}
+
+	return db
+}
+
+// SetPacer implements bernard.PacerSetter, letting bernard.WithPacer share a
+// single rate limiter across several Dropbox-backed syncs. If db is still
+// using its own lazily-created default pacer, that pacer is stopped first,
+// since nothing else can be holding a reference to it.
+func (db *Dropbox) SetPacer(p bernard.Pacer) {
+	db.pacer.Set(p)
+}
+
+// SetLogger implements bernard.LoggerSetter, letting bernard.WithLogger
+// report Dropbox's request and retry activity. It also forwards logger to
+// db.pacer, so backoff sleeps are reported too.
+func (db *Dropbox) SetLogger(logger bernard.Logger) {
+	db.logger = logger
+	db.pacer.SetLogger(logger)
+}
+
+type jsonDecoder func(r io.Reader, v interface{}) error
+
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// ShouldRetry classifies a non-200 response from the Dropbox API, returning
+// whether the request should be retried and, if not, the error to surface.
+// Override it with WithShouldRetry to treat additional error summaries as
+// retryable or terminal.
+type ShouldRetry func(ctx context.Context, statusCode int, errorSummary string) (retry bool, err error)
+
+// defaultShouldRetry retries rate-limit and server-error responses, and
+// treats everything else as terminal.
+func defaultShouldRetry(ctx context.Context, statusCode int, errorSummary string) (bool, error) {
+	switch statusCode {
+	case 429, 500, 502, 503, 504:
+		return true, nil
+	case 401:
+		return false, bernard.ErrInvalidCredentials
+	case 404, 409:
+		return false, fmt.Errorf("%v: %w", errorSummary, bernard.ErrNotFound)
+	default:
+		return false, fmt.Errorf("%v: %w", errorSummary, bernard.ErrNetwork)
+	}
+}
+
+// entry is a single row of a list_folder/list_folder/continue response.
+// Tag is one of "folder", "file" or "deleted".
+type entry struct {
+	Tag            string `json:".tag"`
+	ID             string
+	Name           string
+	PathLower      string `json:"path_lower"`
+	Size           uint64
+	ContentHash    string `json:"content_hash"`
+	ServerModified string `json:"server_modified"`
+}
+
+type listFolderResponse struct {
+	Entries []entry
+	Cursor  string
+	HasMore bool `json:"has_more"`
+}
+
+type errorResponse struct {
+	ErrorSummary string `json:"error_summary"`
+}
+
+// withAuth authorizes and issues a request to path, letting db.pacer govern
+// steady-state QPS and retries of retryable failures (as classified by
+// db.shouldRetry).
+func (db *Dropbox) withAuth(ctx context.Context, path string, body interface{}) (res *http.Response, err error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.withAuth", trace.WithAttributes(attribute.String("http.url", db.baseURL+path)))
+	defer func() { endSpan(span, err) }()
+
+	err = db.pacer.Get().Call(ctx, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+		}
+
+		payload, marshalErr := json.Marshal(body)
+		if marshalErr != nil {
+			return false, marshalErr
+		}
+
+		req, reqErr := http.NewRequestWithContext(ctx, "POST", db.baseURL+path, bytes.NewReader(payload))
+		if reqErr != nil {
+			return false, reqErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		token, _, authErr := db.auth.AccessToken()
+		if authErr != nil {
+			return false, authErr
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		start := time.Now()
+		r, doErr := db.client.Do(req)
+		if doErr != nil {
+			if ctx.Err() != nil {
+				return false, fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+			}
+			db.logger.Warn("bernard: drive request failed", "method", req.Method, "url", req.URL.String(), "latency", time.Since(start), "error", doErr)
+			return true, bernard.ErrNetwork
+		}
+
+		db.logger.Debug("bernard: drive request", "method", req.Method, "url", req.URL.String(), "status", r.StatusCode, "latency", time.Since(start))
+
+		if r.StatusCode == 200 {
+			res = r
+			return false, nil
+		}
+
+		response := new(errorResponse)
+		db.decodeJSON(r.Body, response)
+		r.Body.Close()
+
+		return db.shouldRetry(ctx, r.StatusCode, response.ErrorSummary)
+	})
+
+	return res, err
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// PageToken implements bernard.Driver. It returns a cursor positioned at the
+// current state of driveID, without returning any entries, for use as the
+// starting point of a later ChangedContent call.
+func (db *Dropbox) PageToken(ctx context.Context, driveID string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.PageToken", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	res, err := db.withAuth(ctx, "/files/list_folder/get_latest_cursor", map[string]interface{}{
+		"path":      driveID,
+		"recursive": true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response := new(listFolderResponse)
+	db.decodeJSON(res.Body, response)
+	res.Body.Close()
+
+	return response.Cursor, nil
+}
+
+// DriveInfo implements bernard.Driver. Dropbox has no separate display name
+// for a synced path, so driveID itself is returned.
+func (db *Dropbox) DriveInfo(ctx context.Context, driveID string) (string, error) {
+	return driveID, nil
+}
+
+// AllContent implements bernard.Driver.
+func (db *Dropbox) AllContent(ctx context.Context, driveID string) (_ []ds.Folder, _ []ds.File, err error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.AllContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	var folders []ds.Folder
+	var files []ds.File
+
+	res, err := db.withAuth(ctx, "/files/list_folder", map[string]interface{}{
+		"path":      driveID,
+		"recursive": true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		response := new(listFolderResponse)
+		db.decodeJSON(res.Body, response)
+		res.Body.Close()
+
+		newFolders, newFiles := convert(response.Entries)
+		folders = append(folders, newFolders...)
+		files = append(files, newFiles...)
+
+		if !response.HasMore {
+			break
+		}
+
+		res, err = db.withAuth(ctx, "/files/list_folder/continue", map[string]interface{}{
+			"cursor": response.Cursor,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	orderedFolders := ds.OrderFoldersOnHierarchy(folders)
+	return orderedFolders, files, nil
+}
+
+// StreamAllContent implements bernard.StreamingDriver.
+func (db *Dropbox) StreamAllContent(ctx context.Context, driveID string) (<-chan ds.Batch, <-chan error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.StreamAllContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+
+	batches := make(chan ds.Batch)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer close(batches)
+		defer func() { endSpan(span, err) }()
+		defer func() { errc <- err; close(errc) }()
+
+		var res *http.Response
+		res, err = db.withAuth(ctx, "/files/list_folder", map[string]interface{}{
+			"path":      driveID,
+			"recursive": true,
+		})
+		if err != nil {
+			return
+		}
+
+		for {
+			response := new(listFolderResponse)
+			db.decodeJSON(res.Body, response)
+			res.Body.Close()
+
+			folders, files := convert(response.Entries)
+
+			select {
+			case batches <- ds.Batch{Folders: folders, Files: files}:
+			case <-ctx.Done():
+				err = fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+				return
+			}
+
+			if !response.HasMore {
+				return
+			}
+
+			res, err = db.withAuth(ctx, "/files/list_folder/continue", map[string]interface{}{
+				"cursor": response.Cursor,
+			})
+			if err != nil {
+				return
+			}
+
+			db.logger.Debug("bernard: page token advanced", "drive_id", driveID, "page_token", response.Cursor)
+		}
+	}()
+
+	return batches, errc
+}
+
+// StreamChangedContent implements bernard.StreamingDriver.
+func (db *Dropbox) StreamChangedContent(ctx context.Context, driveID string, pageToken string) (<-chan ds.Batch, <-chan error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.StreamChangedContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+
+	batches := make(chan ds.Batch)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer close(batches)
+		defer func() { endSpan(span, err) }()
+		defer func() { errc <- err; close(errc) }()
+
+		cursor := pageToken
+
+		for {
+			var res *http.Response
+			res, err = db.withAuth(ctx, "/files/list_folder/continue", map[string]interface{}{
+				"cursor": cursor,
+			})
+			if err != nil {
+				return
+			}
+
+			response := new(listFolderResponse)
+			db.decodeJSON(res.Body, response)
+			res.Body.Close()
+
+			var folders []ds.Folder
+			var files []ds.File
+			var removedIDs []string
+
+			for _, e := range response.Entries {
+				if e.Tag == "deleted" {
+					removedIDs = append(removedIDs, e.PathLower)
+					continue
+				}
+
+				newFolders, newFiles := convert([]entry{e})
+				folders = append(folders, newFolders...)
+				files = append(files, newFiles...)
+			}
+
+			cursor = response.Cursor
+			db.logger.Debug("bernard: page token advanced", "drive_id", driveID, "page_token", cursor)
+
+			select {
+			case batches <- ds.Batch{
+				Drive:      ds.Drive{ID: driveID, Name: driveID, PageToken: cursor},
+				Folders:    folders,
+				Files:      files,
+				RemovedIDs: removedIDs,
+			}:
+			case <-ctx.Done():
+				err = fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+				return
+			}
+
+			if !response.HasMore {
+				return
+			}
+		}
+	}()
+
+	return batches, errc
+}
+
+// ChangedContent implements bernard.Driver.
+func (db *Dropbox) ChangedContent(ctx context.Context, driveID string, pageToken string) (_ *ds.ChangedContent, err error) {
+	ctx, span := tracer.Start(ctx, "Dropbox.ChangedContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	var folders []ds.Folder
+	var files []ds.File
+	var removedIDs []string
+
+	cursor := pageToken
+
+	for {
+		res, err := db.withAuth(ctx, "/files/list_folder/continue", map[string]interface{}{
+			"cursor": cursor,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		response := new(listFolderResponse)
+		db.decodeJSON(res.Body, response)
+		res.Body.Close()
+
+		for _, e := range response.Entries {
+			if e.Tag == "deleted" {
+				// Dropbox deletions carry a path, not the original ID, so the
+				// lowercased path is used as the removal key instead.
+				removedIDs = append(removedIDs, e.PathLower)
+				continue
+			}
+
+			newFolders, newFiles := convert([]entry{e})
+			folders = append(folders, newFolders...)
+			files = append(files, newFiles...)
+		}
+
+		cursor = response.Cursor
+
+		if !response.HasMore {
+			break
+		}
+	}
+
+	orderedFolders := ds.OrderFoldersOnHierarchy(folders)
+
+	output := &ds.ChangedContent{
+		Drive:          ds.Drive{ID: driveID, Name: driveID, PageToken: cursor},
+		ChangedFolders: orderedFolders,
+		ChangedFiles:   files,
+		RemovedIDs:     removedIDs,
+	}
+
+	return output, nil
+}
+
+// convert keys every item by its lowercased path rather than e.ID: Dropbox's
+// delete entries carry no ID at all, only a path, so removedIDs (see
+// ChangedContent and StreamChangedContent) is necessarily path-based. Keying
+// folders and files by e.ID here would leave their Parent (also a path) and
+// any later removal unable to match the row's actual ID.
+func convert(entries []entry) (folders []ds.Folder, files []ds.File) {
+	for _, e := range entries {
+		parent := path.Dir(e.PathLower)
+		if parent == "." {
+			parent = ""
+		}
+
+		switch e.Tag {
+		case "folder":
+			folders = append(folders, ds.Folder{
+				ID:     e.PathLower,
+				Name:   e.Name,
+				Parent: parent,
+			})
+		case "file":
+			files = append(files, ds.File{
+				ID:      e.PathLower,
+				Name:    e.Name,
+				Parent:  parent,
+				MD5:     e.ContentHash,
+				Size:    e.Size,
+				ModTime: parseTime(e.ServerModified),
+			})
+		}
+	}
+
+	return folders, files
+}
+
+// parseTime parses an RFC 3339 timestamp as returned by the Dropbox API
+// (e.g. server_modified), returning the zero time if s is empty or
+// malformed.
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
@@ -0,0 +1,21 @@
+package dropbox
+
+import (
+	"testing"
+)
+
+func TestConvertParentMatchesID(t *testing.T) {
+	entries := []entry{
+		{Tag: "folder", ID: "id:root", Name: "Sub", PathLower: "/top/sub"},
+		{Tag: "file", ID: "id:file", Name: "a.txt", PathLower: "/top/sub/a.txt"},
+	}
+
+	folders, files := convert(entries)
+
+	if len(folders) != 1 || folders[0].ID != "/top/sub" {
+		t.Fatalf("folder ID = %+v, want path-keyed ID", folders)
+	}
+	if len(files) != 1 || files[0].Parent != folders[0].ID {
+		t.Fatalf("file.Parent = %q, want it to match the containing folder's ID %q", files[0].Parent, folders[0].ID)
+	}
+}
@@ -0,0 +1,34 @@
+package dropbox
+
+import (
+	"net/http"
+
+	"github.com/l3uddz/bernard"
+)
+
+// Option configures a Dropbox driver at construction time.
+type Option func(*Dropbox)
+
+// WithHTTPClient overrides the http.Client used to issue requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(db *Dropbox) {
+		db.client = client
+	}
+}
+
+// WithPacer overrides the default per-driver Pacer, e.g. to share one
+// rate limiter across several Dropbox instances up front. Equivalent to
+// calling bernard.WithPacer after construction.
+func WithPacer(p bernard.Pacer) Option {
+	return func(db *Dropbox) {
+		db.SetPacer(p)
+	}
+}
+
+// WithShouldRetry overrides how non-200 responses are classified as
+// retryable or terminal, e.g. to also retry on a custom error summary.
+func WithShouldRetry(fn ShouldRetry) Option {
+	return func(db *Dropbox) {
+		db.shouldRetry = fn
+	}
+}
@@ -0,0 +1,643 @@
+// Package googledrive implements bernard.Driver against the Google Drive v3
+// API.
+package googledrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/l3uddz/bernard"
+	ds "github.com/l3uddz/bernard/datastore"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const baseURL = "https://www.googleapis.com/drive/v3"
+
+// tracer emits a span per withAuth call and per bernard.Driver method, so a
+// full-sync or partial-sync can be traced end-to-end alongside Bernard's own
+// spans in a larger service.
+var tracer = otel.Tracer("github.com/l3uddz/bernard/drivers/googledrive")
+
+// GoogleDrive is a bernard.Driver backed by the Google Drive v3 API.
+type GoogleDrive struct {
+	auth    bernard.Authenticator
+	baseURL string
+	client  *http.Client
+	logger  bernard.Logger
+
+	pacer bernard.PacerHolder
+
+	shouldRetry ShouldRetry
+	decodeJSON  jsonDecoder
+}
+
+// New creates a GoogleDrive driver that authorizes requests using auth. Its
+// Pacer is created lazily on first use (see bernard.PacerHolder), so
+// supplying one via WithPacer never leaves a default one running unused.
+func New(auth bernard.Authenticator, opts ...Option) *GoogleDrive {
+	gd := &GoogleDrive{
+		auth:        auth,
+		baseURL:     baseURL,
+		client:      http.DefaultClient,
+		logger:      bernard.NopLogger,
+		shouldRetry: defaultShouldRetry,
+		decodeJSON:  decodeJSON,
+	}
+
+	for _, opt := range opts {
+		opt(gd)
+	}
+
+	return gd
+}
+
+// SetPacer implements bernard.PacerSetter, letting bernard.WithPacer share a
+// single rate limiter across several GoogleDrive-backed syncs. If gd is
+// still using its own lazily-created default pacer, that pacer is stopped
+// first, since nothing else can be holding a reference to it.
+func (gd *GoogleDrive) SetPacer(p bernard.Pacer) {
+	gd.pacer.Set(p)
+}
+
+// SetLogger implements bernard.LoggerSetter, letting bernard.WithLogger
+// report GoogleDrive's request and retry activity. It also forwards logger
+// to gd.pacer, so backoff sleeps are reported too.
+func (gd *GoogleDrive) SetLogger(logger bernard.Logger) {
+	gd.logger = logger
+	gd.pacer.SetLogger(logger)
+}
+
+type driveItem struct {
+	ID          string
+	Name        string
+	MimeType    string
+	Parents     []string
+	Size        uint64 `json:"size,string"`
+	MD5Checksum string
+	Trashed     bool
+	DriveID     string
+
+	ModifiedTime    string
+	CreatedTime     string
+	TrashedTime     string
+	Owners          []driveOwner
+	ShortcutDetails struct {
+		TargetID string `json:"targetId"`
+	}
+	Capabilities struct {
+		CanDownload bool
+	}
+}
+
+type driveOwner struct {
+	EmailAddress string
+	DisplayName  string
+}
+
+type sharedDrive struct {
+	ID   string
+	Name string
+}
+
+type driveChange struct {
+	Drive   sharedDrive
+	DriveID string
+	File    driveItem
+	FileID  string
+	Removed bool
+}
+
+// DriveError is a single error reported by the Drive API, e.g.
+// {Reason: "dailyLimitExceeded"}.
+type DriveError struct {
+	Domain  string
+	Message string
+	Reason  string
+}
+
+type errorResponse struct {
+	Error struct {
+		Errors  []DriveError
+		Code    int
+		Message string
+	}
+}
+
+// ShouldRetry classifies a non-200 response from the Drive API, returning
+// whether the request should be retried and, if not, the error to surface.
+// statusCode is the HTTP status; driveErr is the first error Drive reported,
+// if any. Override it with WithShouldRetry to treat additional 403 reasons
+// as retryable or terminal.
+type ShouldRetry func(ctx context.Context, statusCode int, driveErr DriveError) (retry bool, err error)
+
+// defaultShouldRetry retries rate-limit and server-error responses, and
+// treats everything else as terminal.
+func defaultShouldRetry(ctx context.Context, statusCode int, driveErr DriveError) (bool, error) {
+	switch statusCode {
+	case 429, 500, 502, 503, 504:
+		return true, nil
+	case 401:
+		return false, bernard.ErrInvalidCredentials
+	case 403:
+		switch driveErr.Reason {
+		case "userRateLimitExceeded", "rateLimitExceeded", "sharingRateLimitExceeded":
+			return true, nil
+		default:
+			return false, fmt.Errorf("%v: %w", driveErr.Message, bernard.ErrNetwork)
+		}
+	case 404:
+		return false, fmt.Errorf("%v: %w", driveErr.Message, bernard.ErrNotFound)
+	default:
+		return false, fmt.Errorf("%v: %w", driveErr.Message, bernard.ErrNetwork)
+	}
+}
+
+type jsonDecoder func(r io.Reader, v interface{}) error
+
+// standard JSON decoder
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// withAuth authorizes and issues req, letting gd.pacer govern steady-state
+// QPS and retries of retryable failures (as classified by gd.shouldRetry).
+func (gd *GoogleDrive) withAuth(ctx context.Context, req *http.Request) (res *http.Response, err error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.withAuth", trace.WithAttributes(
+		attribute.String("http.method", req.Method),
+		attribute.String("http.url", req.URL.String()),
+	))
+	defer func() { endSpan(span, err) }()
+
+	err = gd.pacer.Get().Call(ctx, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+		}
+
+		token, _, authErr := gd.auth.AccessToken()
+		if authErr != nil {
+			return false, authErr
+		}
+
+		start := time.Now()
+		req.Header.Set("Authorization", "Bearer "+token)
+		r, doErr := gd.client.Do(req)
+		if doErr != nil {
+			if ctx.Err() != nil {
+				return false, fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+			}
+			gd.logger.Warn("bernard: drive request failed", "method", req.Method, "url", req.URL.String(), "latency", time.Since(start), "error", doErr)
+			return true, bernard.ErrNetwork
+		}
+
+		gd.logger.Debug("bernard: drive request", "method", req.Method, "url", req.URL.String(), "status", r.StatusCode, "latency", time.Since(start))
+
+		if r.StatusCode == 200 {
+			res = r
+			return false, nil
+		}
+
+		response := new(errorResponse)
+		gd.decodeJSON(r.Body, response)
+		r.Body.Close()
+
+		var driveErr DriveError
+		if len(response.Error.Errors) > 0 {
+			driveErr = response.Error.Errors[0]
+		}
+		if driveErr.Message == "" {
+			driveErr.Message = response.Error.Message
+		}
+
+		return gd.shouldRetry(ctx, r.StatusCode, driveErr)
+	})
+
+	return res, err
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// PageToken implements bernard.Driver.
+func (gd *GoogleDrive) PageToken(ctx context.Context, driveID string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.PageToken", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/changes/startPageToken", nil)
+
+	q := url.Values{}
+	q.Add("driveId", driveID)
+	q.Add("supportsAllDrives", "true")
+	req.URL.RawQuery = q.Encode()
+
+	res, err := gd.withAuth(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	type Response struct {
+		StartPageToken string
+	}
+
+	response := new(Response)
+	gd.decodeJSON(res.Body, response)
+	res.Body.Close()
+
+	return response.StartPageToken, nil
+}
+
+// DriveInfo implements bernard.Driver.
+func (gd *GoogleDrive) DriveInfo(ctx context.Context, driveID string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.DriveInfo", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/drives/"+driveID, nil)
+
+	q := url.Values{}
+	q.Add("fields", "name")
+	req.URL.RawQuery = q.Encode()
+
+	res, err := gd.withAuth(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	type Response struct {
+		Name string
+	}
+
+	response := new(Response)
+	gd.decodeJSON(res.Body, response)
+	res.Body.Close()
+
+	return response.Name, nil
+}
+
+// AllContent implements bernard.Driver.
+func (gd *GoogleDrive) AllContent(ctx context.Context, driveID string) (_ []ds.Folder, _ []ds.File, err error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.AllContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+	defer func() { endSpan(span, err) }()
+
+	var files []ds.File
+	var folders []ds.Folder
+	var pageToken string
+
+	for {
+		req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/files", nil)
+
+		q := url.Values{}
+		q.Add("corpora", "drive")
+		q.Add("driveId", driveID)
+		q.Add("pageSize", "1000")
+		q.Add("includeItemsFromAllDrives", "true")
+		q.Add("supportsAllDrives", "true")
+		q.Add("fields", "nextPageToken,files(id,name,mimeType,parents,md5Checksum,size,trashed,modifiedTime,createdTime,trashedTime,owners(emailAddress,displayName),shortcutDetails,capabilities/canDownload)")
+		if pageToken != "" {
+			q.Add("pageToken", pageToken)
+		}
+
+		req.URL.RawQuery = q.Encode()
+
+		res, err := gd.withAuth(ctx, req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		type Response struct {
+			Files         []driveItem
+			NextPageToken string
+		}
+
+		response := new(Response)
+		gd.decodeJSON(res.Body, response)
+		res.Body.Close()
+
+		newFolders, newFiles := convert(response.Files)
+		folders = append(folders, newFolders...)
+		files = append(files, newFiles...)
+
+		pageToken = response.NextPageToken
+		gd.logger.Debug("bernard: page token advanced", "drive_id", driveID, "page_token", pageToken)
+
+		if pageToken == "" {
+			break
+		}
+	}
+
+	orderedFolders := ds.OrderFoldersOnHierarchy(folders)
+	return orderedFolders, files, nil
+}
+
+// StreamAllContent implements bernard.StreamingDriver.
+func (gd *GoogleDrive) StreamAllContent(ctx context.Context, driveID string) (<-chan ds.Batch, <-chan error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.StreamAllContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+
+	batches := make(chan ds.Batch)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer close(batches)
+		defer func() { endSpan(span, err) }()
+		defer func() { errc <- err; close(errc) }()
+
+		var pageToken string
+
+		for {
+			req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/files", nil)
+
+			q := url.Values{}
+			q.Add("corpora", "drive")
+			q.Add("driveId", driveID)
+			q.Add("pageSize", "1000")
+			q.Add("includeItemsFromAllDrives", "true")
+			q.Add("supportsAllDrives", "true")
+			q.Add("fields", "nextPageToken,files(id,name,mimeType,parents,md5Checksum,size,trashed,modifiedTime,createdTime,trashedTime,owners(emailAddress,displayName),shortcutDetails,capabilities/canDownload)")
+			if pageToken != "" {
+				q.Add("pageToken", pageToken)
+			}
+
+			req.URL.RawQuery = q.Encode()
+
+			var res *http.Response
+			res, err = gd.withAuth(ctx, req)
+			if err != nil {
+				return
+			}
+
+			type Response struct {
+				Files         []driveItem
+				NextPageToken string
+			}
+
+			response := new(Response)
+			gd.decodeJSON(res.Body, response)
+			res.Body.Close()
+
+			folders, files := convert(response.Files)
+
+			select {
+			case batches <- ds.Batch{Folders: folders, Files: files}:
+			case <-ctx.Done():
+				err = fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+				return
+			}
+
+			pageToken = response.NextPageToken
+			gd.logger.Debug("bernard: page token advanced", "drive_id", driveID, "page_token", pageToken)
+
+			if pageToken == "" {
+				return
+			}
+		}
+	}()
+
+	return batches, errc
+}
+
+// StreamChangedContent implements bernard.StreamingDriver.
+func (gd *GoogleDrive) StreamChangedContent(ctx context.Context, driveID string, pageToken string) (<-chan ds.Batch, <-chan error) {
+	ctx, span := tracer.Start(ctx, "GoogleDrive.StreamChangedContent", trace.WithAttributes(attribute.String("bernard.drive_id", driveID)))
+
+	batches := make(chan ds.Batch)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer close(batches)
+		defer func() { endSpan(span, err) }()
+		defer func() { errc <- err; close(errc) }()
+
+		drive := ds.Drive{ID: driveID}
+
+		for {
+			req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/changes", nil)
+
+			q := url.Values{}
+			q.Add("driveId", driveID)
+			q.Add("pageSize", "1000")
+			q.Add("pageToken", pageToken)
+			q.Add("includeItemsFromAllDrives", "true")
+			q.Add("supportsAllDrives", "true")
+			q.Add("fields", "nextPageToken,newStartPageToken,changes(driveId,fileId,removed,drive(id,name),file(id,driveId,name,mimeType,parents,md5Checksum,size,trashed,modifiedTime,createdTime,trashedTime,owners(emailAddress,displayName),shortcutDetails,capabilities/canDownload))")
+			req.URL.RawQuery = q.Encode()
+
+			var res *http.Response
+			res, err = gd.withAuth(ctx, req)
+			if err != nil {
+				return
+			}
+
+			type Response struct {
+				NextPageToken     string
+				NewStartPageToken string
+				Changes           []driveChange
+			}
+
+			response := new(Response)
+			gd.decodeJSON(res.Body, response)
+			res.Body.Close()
+
+			var changedItems []driveItem
+			var removedIDs []string
+
+			for _, change := range response.Changes {
+				if change.DriveID != "" {
+					drive.Name = change.Drive.Name
+					continue
+				}
+
+				if change.FileID == "" {
+					continue
+				}
+
+				if change.Removed || change.File.DriveID != driveID {
+					removedIDs = append(removedIDs, change.FileID)
+				} else {
+					changedItems = append(changedItems, change.File)
+				}
+			}
+
+			folders, files := convert(changedItems)
+
+			pageToken = response.NextPageToken
+			drive.PageToken = response.NewStartPageToken
+			gd.logger.Debug("bernard: page token advanced", "drive_id", driveID, "page_token", pageToken)
+
+			select {
+			case batches <- ds.Batch{Drive: drive, Folders: folders, Files: files, RemovedIDs: removedIDs}:
+			case <-ctx.Done():
+				err = fmt.Errorf("%v: %w", ctx.Err(), bernard.ErrCanceled)
+				return
+			}
+
+			if pageToken == "" {
+				return
+			}
+		}
+	}()
+
+	return batches, errc
+}
+
+// ChangedContent implements bernard.Driver.
+func (gd *GoogleDrive) ChangedContent(ctx context.Context, driveID string, pageToken string) (*ds.ChangedContent, error) {
+	var files []ds.File
+	var folders []ds.Folder
+	var removedIDs []string
+
+	drive := ds.Drive{ID: driveID}
+
+	for {
+		req, _ := http.NewRequestWithContext(ctx, "GET", gd.baseURL+"/changes", nil)
+
+		q := url.Values{}
+		q.Add("driveId", driveID)
+		q.Add("pageSize", "1000")
+		q.Add("pageToken", pageToken)
+		q.Add("includeItemsFromAllDrives", "true")
+		q.Add("supportsAllDrives", "true")
+		q.Add("fields", "nextPageToken,newStartPageToken,changes(driveId,fileId,removed,drive(id,name),file(id,driveId,name,mimeType,parents,md5Checksum,size,trashed,modifiedTime,createdTime,trashedTime,owners(emailAddress,displayName),shortcutDetails,capabilities/canDownload))")
+		req.URL.RawQuery = q.Encode()
+
+		res, err := gd.withAuth(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		type Response struct {
+			NextPageToken     string
+			NewStartPageToken string
+			Changes           []driveChange
+		}
+
+		response := new(Response)
+		gd.decodeJSON(res.Body, response)
+		res.Body.Close()
+
+		var changedItems []driveItem
+
+		for _, change := range response.Changes {
+			if change.DriveID != "" {
+				drive.Name = change.Drive.Name
+				continue
+			}
+
+			if change.FileID == "" {
+				continue
+			}
+
+			if change.Removed || change.File.DriveID != driveID {
+				removedIDs = append(removedIDs, change.FileID)
+			} else {
+				changedItems = append(changedItems, change.File)
+			}
+		}
+
+		changedFolders, changedFiles := convert(changedItems)
+		folders = append(folders, changedFolders...)
+		files = append(files, changedFiles...)
+
+		pageToken = response.NextPageToken
+		drive.PageToken = response.NewStartPageToken
+
+		if pageToken == "" {
+			break
+		}
+	}
+
+	orderedFolders := ds.OrderFoldersOnHierarchy(folders)
+
+	output := &ds.ChangedContent{
+		Drive:          drive,
+		ChangedFiles:   files,
+		ChangedFolders: orderedFolders,
+		RemovedIDs:     removedIDs,
+	}
+
+	return output, nil
+}
+
+func convert(content []driveItem) (folders []ds.Folder, files []ds.File) {
+	for _, item := range content {
+		owners := convertOwners(item.Owners)
+
+		// A shortcut is never itself a folder, even when it points at one,
+		// so it is always stored as a file with ShortcutTargetID set for
+		// callers to dereference.
+		if item.MimeType == "application/vnd.google-apps.folder" {
+			folders = append(folders, ds.Folder{
+				ID:          item.ID,
+				Name:        item.Name,
+				Parent:      item.Parents[0],
+				Trashed:     item.Trashed,
+				MimeType:    item.MimeType,
+				ModTime:     parseTime(item.ModifiedTime),
+				CreatedTime: parseTime(item.CreatedTime),
+				TrashedTime: parseTime(item.TrashedTime),
+				Owners:      owners,
+				CanDownload: item.Capabilities.CanDownload,
+			})
+		} else {
+			files = append(files, ds.File{
+				ID:               item.ID,
+				Name:             item.Name,
+				Parent:           item.Parents[0],
+				Trashed:          item.Trashed,
+				MD5:              item.MD5Checksum,
+				Size:             item.Size,
+				MimeType:         item.MimeType,
+				ModTime:          parseTime(item.ModifiedTime),
+				CreatedTime:      parseTime(item.CreatedTime),
+				TrashedTime:      parseTime(item.TrashedTime),
+				Owners:           owners,
+				ShortcutTargetID: item.ShortcutDetails.TargetID,
+				CanDownload:      item.Capabilities.CanDownload,
+			})
+		}
+	}
+
+	return folders, files
+}
+
+func convertOwners(owners []driveOwner) []ds.Owner {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	converted := make([]ds.Owner, len(owners))
+	for i, o := range owners {
+		converted[i] = ds.Owner{EmailAddress: o.EmailAddress, DisplayName: o.DisplayName}
+	}
+
+	return converted
+}
+
+// parseTime parses an RFC 3339 timestamp as returned by the Drive API (e.g.
+// modifiedTime), returning the zero time if s is empty or malformed.
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
@@ -0,0 +1,94 @@
+package googledrive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/l3uddz/bernard"
+)
+
+func TestConvertSplitsFoldersAndFiles(t *testing.T) {
+	content := []driveItem{
+		{ID: "folder1", Name: "Sub", MimeType: "application/vnd.google-apps.folder", Parents: []string{"root"}},
+		{ID: "file1", Name: "a.txt", Parents: []string{"folder1"}, MD5Checksum: "abc", Size: 3},
+	}
+
+	folders, files := convert(content)
+
+	if len(folders) != 1 || folders[0].ID != "folder1" || folders[0].Parent != "root" {
+		t.Fatalf("folders = %+v, want one folder with ID folder1 under root", folders)
+	}
+	if len(files) != 1 || files[0].ID != "file1" || files[0].Parent != "folder1" {
+		t.Fatalf("files = %+v, want one file with ID file1 under folder1", files)
+	}
+}
+
+func TestConvertShortcutIsAlwaysAFile(t *testing.T) {
+	content := []driveItem{
+		{
+			ID:       "shortcut1",
+			Name:     "link to folder",
+			Parents:  []string{"root"},
+			MimeType: "application/vnd.google-apps.shortcut",
+			ShortcutDetails: struct {
+				TargetID string `json:"targetId"`
+			}{TargetID: "folder1"},
+		},
+	}
+
+	folders, files := convert(content)
+
+	if len(folders) != 0 {
+		t.Fatalf("folders = %+v, want a shortcut stored as a file even when it targets a folder", folders)
+	}
+	if len(files) != 1 || files[0].ShortcutTargetID != "folder1" {
+		t.Fatalf("files = %+v, want ShortcutTargetID = folder1", files)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := parseTime(want.Format(time.RFC3339))
+	if !got.Equal(want) {
+		t.Fatalf("parseTime(%q) = %v, want %v", want.Format(time.RFC3339), got, want)
+	}
+
+	for _, s := range []string{"", "not-a-timestamp"} {
+		if got := parseTime(s); !got.IsZero() {
+			t.Errorf("parseTime(%q) = %v, want the zero time", s, got)
+		}
+	}
+}
+
+func TestDefaultShouldRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		driveErr   DriveError
+		wantRetry  bool
+		wantErr    error
+	}{
+		{"rate limited", 429, DriveError{}, true, nil},
+		{"server error", 503, DriveError{}, true, nil},
+		{"unauthorized", 401, DriveError{}, false, bernard.ErrInvalidCredentials},
+		{"user rate limit reason", 403, DriveError{Reason: "userRateLimitExceeded"}, true, nil},
+		{"other 403 reason", 403, DriveError{Reason: "accessNotConfigured"}, false, bernard.ErrNetwork},
+		{"not found", 404, DriveError{}, false, bernard.ErrNotFound},
+		{"other status", 418, DriveError{}, false, bernard.ErrNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, err := defaultShouldRetry(context.Background(), tt.statusCode, tt.driveErr)
+			if retry != tt.wantRetry {
+				t.Errorf("retry = %v, want %v", retry, tt.wantRetry)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want it to wrap %v", err, tt.wantErr)
+			}
+		})
+	}
+}
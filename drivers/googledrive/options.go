@@ -0,0 +1,34 @@
+package googledrive
+
+import (
+	"net/http"
+
+	"github.com/l3uddz/bernard"
+)
+
+// Option configures a GoogleDrive driver at construction time.
+type Option func(*GoogleDrive)
+
+// WithHTTPClient overrides the http.Client used to issue requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(gd *GoogleDrive) {
+		gd.client = client
+	}
+}
+
+// WithPacer overrides the default per-driver Pacer, e.g. to share one
+// rate limiter across several GoogleDrive instances up front. Equivalent to
+// calling bernard.WithPacer after construction.
+func WithPacer(p bernard.Pacer) Option {
+	return func(gd *GoogleDrive) {
+		gd.SetPacer(p)
+	}
+}
+
+// WithShouldRetry overrides how non-200 responses are classified as
+// retryable or terminal, e.g. to also retry on a custom 403 reason.
+func WithShouldRetry(fn ShouldRetry) Option {
+	return func(gd *GoogleDrive) {
+		gd.shouldRetry = fn
+	}
+}
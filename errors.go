@@ -0,0 +1,23 @@
+package bernard
+
+import "errors"
+
+var (
+	// ErrNetwork is returned when a request could not be completed due to a
+	// network-level failure or an unrecognised API error.
+	ErrNetwork = errors.New("bernard: network error")
+	// ErrInvalidCredentials is returned when the Authenticator's access token
+	// was rejected by the API.
+	ErrInvalidCredentials = errors.New("bernard: invalid credentials")
+	// ErrNotFound is returned when the requested drive or item does not exist.
+	ErrNotFound = errors.New("bernard: not found")
+	// ErrCanceled is returned when a sync is aborted because its context was
+	// canceled or its deadline exceeded, including while a backoff sleep was
+	// in progress. Callers can use errors.Is to distinguish this from a
+	// genuine network failure.
+	ErrCanceled = errors.New("bernard: canceled")
+	// ErrStreamingUnsupported is returned by FullSyncStreamContext and
+	// PartialSyncStreamContext when the configured driver or datastore does
+	// not implement StreamingDriver or ds.StreamingDatastore.
+	ErrStreamingUnsupported = errors.New("bernard: streaming not supported by driver or datastore")
+)
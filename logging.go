@@ -0,0 +1,41 @@
+package bernard
+
+// Logger is a structured logger compatible with *log/slog.Logger — any
+// *slog.Logger satisfies it directly. Set one with WithLogger to observe
+// request, retry and sync activity that is otherwise invisible.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// NopLogger discards everything. It is the default Logger so callers and
+// drivers never need a nil check.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// LoggerSetter is implemented by drivers and pacers that can report their
+// activity to a Logger. WithLogger uses it.
+type LoggerSetter interface {
+	SetLogger(Logger)
+}
+
+// WithLogger sets logger as the Bernard instance's Logger, and propagates it
+// to the driver and, transitively, its Pacer when either implements
+// LoggerSetter. It is a no-op on components that do not.
+func WithLogger(logger Logger) Option {
+	return func(b *Bernard) {
+		b.logger = logger
+
+		if setter, ok := b.driver.(LoggerSetter); ok {
+			setter.SetLogger(logger)
+		}
+	}
+}
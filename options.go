@@ -0,0 +1,4 @@
+package bernard
+
+// Option configures a Bernard instance at construction time.
+type Option func(*Bernard)
@@ -0,0 +1,252 @@
+package bernard
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Pacer paces and retries calls to a remote API. A driver wraps each request
+// in a call to Call; the pacer enforces steady-state throughput (typically a
+// token bucket) and retries fn, backing off between attempts, for as long as
+// fn reports retry=true. Call returns promptly with an error wrapping
+// ErrCanceled if ctx is done, including while waiting for a token or during a
+// backoff sleep.
+type Pacer interface {
+	Call(ctx context.Context, fn func() (retry bool, err error)) error
+}
+
+// PacerSetter is implemented by drivers that can share a single Pacer across
+// multiple Bernard instances, e.g. to keep several synced drives under one
+// account-wide QPS budget. WithPacer uses it.
+type PacerSetter interface {
+	SetPacer(Pacer)
+}
+
+// Stoppable is implemented by Pacers that own background resources, such as
+// TokenBucketPacer's refill goroutine, which must be released with Stop once
+// the pacer is no longer reachable.
+type Stoppable interface {
+	Stop()
+}
+
+// PacerHolder manages a driver's Pacer, lazily creating the default
+// NewDrivePacer the first time Get is called. It tracks whether it created
+// that pacer itself, so Set only ever Stops a pacer nothing else could be
+// holding a reference to: one supplied through Set (e.g. via WithPacer) may
+// be shared across several driver instances and must never be stopped
+// implicitly. Safe for concurrent use; the zero value is ready to use. A
+// driver embeds a PacerHolder and implements PacerSetter and the
+// pacer-forwarding half of LoggerSetter by delegating to it.
+type PacerHolder struct {
+	mu     sync.Mutex
+	pacer  Pacer
+	owns   bool
+	logger Logger
+}
+
+// Get returns the held Pacer, lazily creating NewDrivePacer the first time
+// one is needed.
+func (h *PacerHolder) Get() Pacer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pacer == nil {
+		p := NewDrivePacer()
+		if h.logger != nil {
+			p.SetLogger(h.logger)
+		}
+		h.pacer = p
+		h.owns = true
+	}
+
+	return h.pacer
+}
+
+// Set replaces the held Pacer with p, implementing PacerSetter. If the
+// outgoing pacer is the default h.Get() created itself, it is Stopped
+// first; an externally supplied pacer is left running, since it may still
+// be in use elsewhere.
+func (h *PacerHolder) Set(p Pacer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.owns {
+		if old, ok := h.pacer.(Stoppable); ok {
+			old.Stop()
+		}
+	}
+
+	h.pacer = p
+	h.owns = false
+}
+
+// SetLogger records logger for the next pacer Get lazily creates, and
+// forwards it to the current pacer if one is already held and implements
+// LoggerSetter.
+func (h *PacerHolder) SetLogger(logger Logger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.logger = logger
+	if setter, ok := h.pacer.(LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+// WithPacer shares p as the driver's Pacer, replacing whatever pacer it was
+// constructed with. It is a no-op if driver does not implement PacerSetter.
+// If b already has a Logger set (regardless of option order), it is
+// forwarded to p when p implements LoggerSetter.
+func WithPacer(p Pacer) Option {
+	return func(b *Bernard) {
+		if setter, ok := b.driver.(PacerSetter); ok {
+			setter.SetPacer(p)
+		}
+
+		if setter, ok := p.(LoggerSetter); ok {
+			setter.SetLogger(b.logger)
+		}
+	}
+}
+
+// TokenBucketPacer is the default Pacer: a token bucket limits steady-state
+// QPS, and a retried call backs off using decorrelated jitter (as described
+// in the AWS Architecture Blog's "Exponential Backoff And Jitter") bounded by
+// [minSleep, maxSleep], up to maxRetries attempts.
+type TokenBucketPacer struct {
+	maxRetries int
+	minSleep   time.Duration
+	maxSleep   time.Duration
+	tokens     chan struct{}
+	ticker     *time.Ticker
+	logger     Logger
+
+	mu        sync.Mutex
+	lastSleep time.Duration
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTokenBucketPacer creates a TokenBucketPacer allowing qps calls per
+// second. Retried calls back off between minSleep and maxSleep, and are
+// abandoned after maxRetries attempts.
+func NewTokenBucketPacer(qps float64, minSleep, maxSleep time.Duration, maxRetries int) *TokenBucketPacer {
+	p := &TokenBucketPacer{
+		maxRetries: maxRetries,
+		minSleep:   minSleep,
+		maxSleep:   maxSleep,
+		lastSleep:  minSleep,
+		tokens:     make(chan struct{}, 1),
+		ticker:     time.NewTicker(time.Duration(float64(time.Second) / qps)),
+		logger:     NopLogger,
+		done:       make(chan struct{}),
+	}
+
+	p.tokens <- struct{}{}
+
+	go func() {
+		for {
+			select {
+			case <-p.ticker.C:
+				select {
+				case p.tokens <- struct{}{}:
+				default:
+				}
+			case <-p.done:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// NewDrivePacer creates the TokenBucketPacer used by default for the Google
+// Drive API: 10 requests/s steady state, backing off between 100ms and 32s
+// for up to 10 retries.
+func NewDrivePacer() *TokenBucketPacer {
+	return NewTokenBucketPacer(10, 100*time.Millisecond, 32*time.Second, 10)
+}
+
+// SetLogger implements LoggerSetter, reporting backoff sleeps to logger.
+func (p *TokenBucketPacer) SetLogger(logger Logger) {
+	p.logger = logger
+}
+
+// Call implements Pacer.
+func (p *TokenBucketPacer) Call(ctx context.Context, fn func() (retry bool, err error)) error {
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-p.tokens:
+		case <-ctx.Done():
+			return fmt.Errorf("%v: %w", ctx.Err(), ErrCanceled)
+		}
+
+		var retry bool
+		retry, err = fn()
+		if !retry {
+			return err
+		}
+
+		if attempt >= p.maxRetries {
+			p.logger.Warn("bernard: pacer retry budget exhausted", "attempt", attempt, "error", err)
+			return err
+		}
+
+		wait := p.nextBackoff()
+		p.logger.Debug("bernard: pacer backing off", "attempt", attempt, "wait", wait, "error", err)
+		if err := ctxSleep(ctx, wait); err != nil {
+			return fmt.Errorf("%v: %w", ctx.Err(), ErrCanceled)
+		}
+	}
+}
+
+// ctxSleep waits out d, or returns ctx.Err() if ctx is done first.
+func ctxSleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// nextBackoff returns the next decorrelated-jitter sleep duration:
+// random_between(minSleep, lastSleep*3), capped at maxSleep.
+func (p *TokenBucketPacer) nextBackoff() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	upper := p.lastSleep * 3
+	if upper > p.maxSleep {
+		upper = p.maxSleep
+	}
+	if upper <= p.minSleep {
+		p.lastSleep = p.minSleep
+		return p.minSleep
+	}
+
+	sleep := p.minSleep + time.Duration(rand.Int63n(int64(upper-p.minSleep)))
+	p.lastSleep = sleep
+	return sleep
+}
+
+// Stop releases the pacer's background ticker and refill goroutine. Safe to
+// call once a Bernard using this pacer is done syncing, and safe to call
+// more than once (e.g. SetPacer stopping a pacer a caller also owns
+// directly).
+func (p *TokenBucketPacer) Stop() {
+	p.stopOnce.Do(func() {
+		p.ticker.Stop()
+		close(p.done)
+	})
+}
@@ -0,0 +1,118 @@
+package bernard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketPacerCallCanceledDuringBackoff(t *testing.T) {
+	p := NewTokenBucketPacer(1000, time.Hour, time.Hour, 10)
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Call(ctx, func() (bool, error) {
+			attempts++
+			if attempts == 1 {
+				return true, errors.New("retryable")
+			}
+			return false, nil
+		})
+	}()
+
+	// Give the pacer time to enter its (hour-long) backoff sleep, then cancel.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCanceled) {
+			t.Fatalf("Call() error = %v, want ErrCanceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Call() did not return promptly after context cancellation during backoff")
+	}
+}
+
+func TestTokenBucketPacerCallCanceledWaitingForToken(t *testing.T) {
+	// qps is deliberately tiny so the ticker can't refill p.tokens and race
+	// the already-canceled ctx.Done() in Call's select.
+	p := NewTokenBucketPacer(0.001, time.Millisecond, time.Millisecond, 10)
+	defer p.Stop()
+
+	<-p.tokens // drain the only token so the next Call blocks waiting for one
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := p.Call(ctx, func() (bool, error) {
+		t.Fatal("fn should not run once ctx is already canceled")
+		return false, nil
+	})
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Call() error = %v, want ErrCanceled", err)
+	}
+}
+
+func TestTokenBucketPacerStopIsIdempotent(t *testing.T) {
+	p := NewTokenBucketPacer(1000, time.Millisecond, time.Millisecond, 10)
+
+	p.Stop()
+	p.Stop() // a caller sharing p with bernard.WithPacer may also Stop it directly
+}
+
+// stoppablePacer is a Pacer that also implements Stoppable, recording
+// whether it was stopped.
+type stoppablePacer struct{ stopped bool }
+
+func (p *stoppablePacer) Call(context.Context, func() (bool, error)) error { return nil }
+func (p *stoppablePacer) Stop()                                            { p.stopped = true }
+
+func TestPacerHolderGetLazilyCreatesAnOwnedDefault(t *testing.T) {
+	var h PacerHolder
+
+	p := h.Get()
+	if p == nil {
+		t.Fatal("Get should lazily create a default pacer")
+	}
+	if h.Get() != p {
+		t.Error("Get should return the same pacer on subsequent calls")
+	}
+
+	if stoppable, ok := p.(Stoppable); ok {
+		stoppable.Stop()
+	}
+}
+
+func TestPacerHolderSetStopsItsOwnLazilyCreatedDefault(t *testing.T) {
+	var h PacerHolder
+
+	old := h.Get().(*TokenBucketPacer) // lazily created, so owned by h
+	h.Set(&stoppablePacer{})
+
+	select {
+	case <-old.done:
+	default:
+		t.Error("Set did not stop its own lazily-created default pacer")
+	}
+}
+
+func TestPacerHolderSetDoesNotStopAnExternallySuppliedPacer(t *testing.T) {
+	// A pacer reaching the holder only via Set (e.g. bernard.WithPacer) may
+	// be shared with other driver instances (that's the feature's whole
+	// point), so replacing it must never implicitly stop it.
+	shared := &stoppablePacer{}
+	var h PacerHolder
+	h.Set(shared)
+
+	h.Set(&stoppablePacer{})
+
+	if shared.stopped {
+		t.Error("Set stopped an externally supplied pacer that may still be shared elsewhere")
+	}
+}
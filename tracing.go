@@ -0,0 +1,8 @@
+package bernard
+
+import "go.opentelemetry.io/otel"
+
+// tracer emits spans for each sync entry point, so a full-sync or
+// partial-sync can be traced end-to-end alongside the driver's own
+// per-request spans in a larger service.
+var tracer = otel.Tracer("github.com/l3uddz/bernard")